@@ -0,0 +1,126 @@
+package renter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/crypto"
+)
+
+// checkpointExt is the extension given to on-disk upload checkpoints. A
+// checkpoint is kept alongside the .sia file for as long as an upload is
+// in progress, and is removed once the upload completes with every chunk at
+// full health (see DegradedChunks for the case where it is kept).
+const checkpointExt = ".checkpoint"
+
+// uploadCheckpoint records enough state about an in-progress upload that it
+// can be resumed after the process exits without re-uploading the chunks
+// that were already fully uploaded. Pieces of the chunk that was in progress
+// are not recorded, and are uploaded again on resume.
+type uploadCheckpoint struct {
+	Nickname   string // nickname of the file being uploaded
+	Filename   string // path to the source file on disk
+	ChunkIndex uint64 // index of the next chunk that has not been fully uploaded
+
+	// PieceBitmaps tracks, per host (keyed by the host's contract IP), which
+	// piece indices of the current chunk that host has already acknowledged.
+	// Bit i of the bitmap is set once the host has ACKed piece i. This caps
+	// supported erasure codes at maxCheckpointPieces total pieces.
+	PieceBitmaps map[string]uint32
+
+	// Contracts holds the fileContract negotiated with each host so far, so
+	// that a resumed upload can carry them forward instead of renegotiating.
+	Contracts map[string]fileContract
+
+	// DataPieces, ParityPieces, PieceSize, MasterKey, FileSize, and FileMode
+	// are everything needed to reconstruct the *file for this upload from
+	// scratch. A .sia file only gets written once every chunk has been
+	// resolved (see Renter.saveFile), so an upload that crashes mid-flight
+	// never repopulates r.files on restart; the checkpoint itself has to
+	// carry enough to rebuild it, or ResumeUpload would only ever work for
+	// a cancel-then-resume within the same process lifetime.
+	DataPieces   int
+	ParityPieces int
+	PieceSize    uint64
+	MasterKey    crypto.TwofishKey
+	FileSize     uint64
+	FileMode     uint32
+
+	// DegradedChunks lists the indices of chunks that finished with fewer
+	// pieces placed than were attempted. A checkpoint is kept on disk even
+	// after an upload otherwise completes as long as this is non-empty, so
+	// that a repair pass has somewhere to find the source file and the
+	// contracts already in place.
+	DegradedChunks []uint64
+}
+
+// checkpointPath returns the path of the on-disk checkpoint for nickname.
+func (r *Renter) checkpointPath(nickname string) string {
+	return filepath.Join(r.persistDir, nickname+checkpointExt)
+}
+
+// saveCheckpoint atomically writes cp to disk, overwriting any previous
+// checkpoint for the same nickname.
+func (r *Renter) saveCheckpoint(cp *uploadCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	path := r.checkpointPath(cp.Nickname)
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// loadCheckpoint reads the on-disk checkpoint for nickname, if one exists.
+func (r *Renter) loadCheckpoint(nickname string) (*uploadCheckpoint, error) {
+	data, err := ioutil.ReadFile(r.checkpointPath(nickname))
+	if err != nil {
+		return nil, err
+	}
+	var cp uploadCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// deleteCheckpoint removes the on-disk checkpoint for nickname. It is not
+// an error for the checkpoint to be absent.
+func (r *Renter) deleteCheckpoint(nickname string) error {
+	err := os.Remove(r.checkpointPath(nickname))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// hasCheckpoint reports whether an on-disk checkpoint exists for nickname.
+func (r *Renter) hasCheckpoint(nickname string) bool {
+	_, err := os.Stat(r.checkpointPath(nickname))
+	return err == nil
+}
+
+// reconstituteFile rebuilds the *file for cp's upload from the erasure
+// code, piece size, master key, and size recorded in it. It is used by
+// ResumeUpload when r.files has no entry for cp.Nickname: that happens
+// whenever the upload crashed before ever reaching f.upload's final
+// saveFile, since nothing else repopulates r.files for an unfinished
+// upload after a process restart.
+func (r *Renter) reconstituteFile(cp *uploadCheckpoint) (*file, error) {
+	ec, err := NewRSCode(cp.DataPieces, cp.ParityPieces)
+	if err != nil {
+		return nil, err
+	}
+	f := newFile(cp.Nickname, ec, cp.PieceSize, cp.FileSize)
+	f.masterKey = cp.MasterKey
+	f.mode = cp.FileMode
+	for ip, contract := range cp.Contracts {
+		f.contracts[ip] = contract
+	}
+	return f, nil
+}
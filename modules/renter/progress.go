@@ -0,0 +1,60 @@
+package renter
+
+import "sync"
+
+// HostUploadStats summarizes what has been uploaded to a single host so far
+// during an upload.
+type HostUploadStats struct {
+	PiecesUploaded uint64
+	BytesUploaded  uint64
+}
+
+// UploadProgress is emitted on the channel returned by
+// Renter.UploadWithContext as an upload proceeds. The final value sent
+// before the channel is closed carries the terminal error, if any, that the
+// upload ended with; a nil Err means the upload finished at full health.
+type UploadProgress struct {
+	Nickname      string
+	ChunksDone    uint64
+	ChunksTotal   uint64
+	BytesUploaded uint64
+	BytesTotal    uint64
+	PerHostStats  map[string]HostUploadStats
+	Err           error
+}
+
+// progressTracker accumulates per-host upload statistics across the
+// lifetime of an upload, for reporting via UploadProgress. Unlike
+// checkpointState, which tracks bitmaps scoped to a single chunk,
+// progressTracker's counters only ever grow.
+type progressTracker struct {
+	mu    sync.Mutex
+	hosts map[string]HostUploadStats
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{hosts: make(map[string]HostUploadStats)}
+}
+
+// recordPiece adds a delivered piece of the given size to host's running
+// totals.
+func (p *progressTracker) recordPiece(host string, size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.hosts[host]
+	stats.PiecesUploaded++
+	stats.BytesUploaded += uint64(size)
+	p.hosts[host] = stats
+}
+
+// snapshot returns a copy of the current per-host statistics, suitable for
+// embedding in an UploadProgress.
+func (p *progressTracker) snapshot() map[string]HostUploadStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]HostUploadStats, len(p.hosts))
+	for host, stats := range p.hosts {
+		out[host] = stats
+	}
+	return out
+}
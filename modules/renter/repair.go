@@ -0,0 +1,143 @@
+package renter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+)
+
+// maxRepairAttempts bounds how many times scheduleRepair retries a single
+// chunk before giving up on it for this pass. repairChunk's doc comment
+// promises that "a later repair pass can find and retry" a chunk that comes
+// back still degraded or fatal; this loop is that pass, rather than a
+// fire-and-forget call that leaves the promise unfulfilled.
+const maxRepairAttempts = 3
+
+// scheduleRepair queues a background attempt to bring the given chunks of
+// nickname back up to full replication. It returns immediately; the repair
+// itself runs on its own goroutine and is best-effort, retrying each chunk
+// up to maxRepairAttempts times before logging and moving on, so a chunk
+// that still can't be repaired is never silently abandoned.
+func (r *Renter) scheduleRepair(nickname string, chunkIndices []uint64) {
+	go func() {
+		for _, chunkIndex := range chunkIndices {
+			var err error
+			for attempt := 1; attempt <= maxRepairAttempts; attempt++ {
+				err = r.repairChunk(nickname, chunkIndex)
+				if err == nil {
+					break
+				}
+			}
+			if err != nil {
+				log.Printf("renter: giving up on repairing chunk %d of %q after %d attempts: %v", chunkIndex, nickname, maxRepairAttempts, err)
+			}
+		}
+	}()
+}
+
+// repairChunk re-encodes a single chunk of nickname's source file and
+// uploads it to a fresh set of hosts. It relies on the checkpoint left
+// behind by an upload that finished degraded, since that is the only place
+// the source file's path is still recorded once the upload itself has
+// completed.
+//
+// The chunk's entry in the on-disk checkpoint's DegradedChunks list is only
+// cleared once every piece has actually been placed; if the repair itself
+// falls short (whether still recoverable or, worse, below the erasure
+// code's minimum), the chunk and its checkpoint are left in place so a
+// later repair pass can find and retry it, rather than being silently
+// dropped and mistaken for a healthy chunk.
+func (r *Renter) repairChunk(nickname string, chunkIndex uint64) error {
+	lockID := r.mu.RLock()
+	f, exists := r.files[nickname]
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return errors.New("repairChunk: no file found for nickname")
+	}
+
+	cp, err := r.loadCheckpoint(nickname)
+	if err != nil {
+		return errors.New("repairChunk: no checkpoint found for nickname")
+	}
+
+	handle, err := os.Open(cp.Filename)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	if _, err := handle.Seek(int64(chunkIndex*f.chunkSize()), io.SeekStart); err != nil {
+		return err
+	}
+	chunk := make([]byte, f.chunkSize())
+	if _, err := io.ReadFull(handle, chunk); err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	pieces, err := f.erasureCode.Encode(chunk)
+	if err != nil {
+		return err
+	}
+
+	totalsize := f.pieceSize * uint64(f.erasureCode.NumPieces())
+	hosts, err := r.connectHosts(f.erasureCode.MinPieces(), f.erasureCode.NumPieces()*3/2, totalsize, defaultDuration, f.masterKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, host := range hosts {
+			host.Close()
+		}
+	}()
+
+	// used tracks which hosts have already taken a piece of this chunk, so
+	// that as long as there are at least as many hosts as pieces, each
+	// piece lands on a distinct host instead of every piece piling onto
+	// whichever host happens to be first in hosts and keeps accepting —
+	// which would defeat the repair by leaving the chunk just as
+	// concentrated on a single host as before.
+	placed := 0
+	used := make(map[string]bool, len(hosts))
+	for j, data := range pieces {
+		for k := 0; k < len(hosts); k++ {
+			host := hosts[(j+k)%len(hosts)]
+			ip := string(host.fileContract().IP)
+			if used[ip] {
+				continue
+			}
+			if err := host.addPiece(context.Background(), uploadPiece{data, chunkIndex, uint64(j)}); err != nil {
+				continue
+			}
+			contract := host.fileContract()
+			f.contracts[contract.IP] = contract
+			used[string(contract.IP)] = true
+			placed++
+			break
+		}
+	}
+
+	if placed < f.erasureCode.MinPieces() {
+		// still below the reconstructable threshold; leave the checkpoint
+		// and the chunk's DegradedChunks entry untouched for the next pass.
+		return &chunkFatalError{chunkIndex, placed, f.erasureCode.MinPieces()}
+	}
+	if placed < len(pieces) {
+		// improved, but still under-replicated; leave it flagged rather
+		// than reporting a clean repair that didn't actually happen.
+		return &chunkDegradedError{[]uint64{chunkIndex}}
+	}
+
+	// every piece landed; this chunk is no longer degraded
+	remaining := cp.DegradedChunks[:0]
+	for _, idx := range cp.DegradedChunks {
+		if idx != chunkIndex {
+			remaining = append(remaining, idx)
+		}
+	}
+	cp.DegradedChunks = remaining
+	if len(cp.DegradedChunks) == 0 {
+		return r.deleteCheckpoint(nickname)
+	}
+	return r.saveCheckpoint(cp)
+}
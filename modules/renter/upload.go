@@ -1,10 +1,14 @@
 package renter
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"math/bits"
 	"os"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 
 	"github.com/NebulousLabs/Sia/crypto"
@@ -23,6 +27,19 @@ const (
 	// property, revisions break the file's Merkle root.
 	defaultPieceSize = 1<<22 - crypto.TwofishOverhead // 4 MiB
 	smallPieceSize   = 1<<16 - crypto.TwofishOverhead // 64 KiB
+
+	// maxPieceAttempts bounds how many times a single piece will be
+	// re-dispatched to a different host after an addPiece failure before it
+	// is given up on for the current chunk.
+	maxPieceAttempts = 3
+
+	// maxCheckpointPieces is the largest number of total pieces an erasure
+	// code may use: checkpointState.pieceBitmaps packs a chunk's pieces into
+	// a uint32 per host, one bit per piece index, so anything above this
+	// would silently lose acknowledgements for the pieces that don't fit.
+	// UploadWithContext and ResumeUpload both reject configurations above
+	// this before any upload work begins.
+	maxCheckpointPieces = 32
 )
 
 type uploadPiece struct {
@@ -31,36 +48,186 @@ type uploadPiece struct {
 	pieceIndex uint64
 }
 
+// uploadAck is sent by an uploadWorker after it has successfully delivered
+// a single uploadPiece. The coordinator in upload uses these, together with
+// retryChan, to know when a chunk has been fully resolved (either
+// delivered, or given up on) by every host still standing.
+type uploadAck struct {
+	host       string
+	pieceIndex uint64
+}
+
+// chunkDegradedError is returned by upload when one or more chunks ended up
+// with fewer pieces placed than were attempted, but still enough to satisfy
+// the erasure code's minimum, meaning the file is readable but under-
+// replicated until it is repaired.
+type chunkDegradedError struct {
+	chunks []uint64
+}
+
+func (e *chunkDegradedError) Error() string {
+	return fmt.Sprintf("upload completed but %d chunk(s) are under-replicated and need repair", len(e.chunks))
+}
+
+// chunkFatalError is returned by upload when a chunk did not get enough
+// pieces placed to satisfy the erasure code's minimum, meaning the chunk
+// (and therefore the file) cannot be reconstructed.
+type chunkFatalError struct {
+	chunkIndex           uint64
+	piecesPlaced, needed int
+}
+
+func (e *chunkFatalError) Error() string {
+	return fmt.Sprintf("chunk %v: only %v of %v needed pieces were placed", e.chunkIndex, e.piecesPlaced, e.needed)
+}
+
 // An uploader uploads pieces to a host. This interface exists to facilitate
 // easy testing.
 type uploader interface {
-	// addPiece uploads a piece to the uploader.
-	addPiece(uploadPiece) error
+	// addPiece uploads a piece to the uploader. It should return promptly
+	// with ctx.Err() if ctx is cancelled before the RPC completes.
+	addPiece(ctx context.Context, piece uploadPiece) error
 
 	// fileContract returns the fileContract containing the metadata of all
 	// previously added pieces.
 	fileContract() fileContract
+
+	// Close tears down the uploader's connection to its host.
+	Close() error
+}
+
+// checkpointState tracks, for the chunk currently being uploaded, which
+// pieces each host has already acknowledged. It is consulted before
+// re-sending a piece to a host so that a piece a host already has is never
+// uploaded to it twice, and it is what gets persisted into an
+// uploadCheckpoint at each chunk boundary. Bitmaps are a uint32 per host, so
+// this only supports erasure codes with up to maxCheckpointPieces total
+// pieces; callers are expected to enforce that ceiling before an upload
+// with more pieces than that ever reaches a checkpointState.
+type checkpointState struct {
+	mu           sync.Mutex
+	pieceBitmaps map[string]uint32
+}
+
+// newCheckpointState returns an empty checkpointState. It always starts
+// empty, even when resuming an upload: the bitmaps recorded in an
+// uploadCheckpoint describe the chunk the checkpoint was saved for, which is
+// already fully uploaded, not the chunk a resumed upload actually starts at.
+func newCheckpointState() *checkpointState {
+	return &checkpointState{pieceBitmaps: make(map[string]uint32)}
+}
+
+// hasPiece reports whether host has already acknowledged pieceIndex for the
+// current chunk.
+func (c *checkpointState) hasPiece(host string, pieceIndex uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pieceBitmaps[host]&(1<<pieceIndex) != 0
+}
+
+// ackPiece atomically records that host has acknowledged pieceIndex for the
+// current chunk.
+func (c *checkpointState) ackPiece(host string, pieceIndex uint64) {
+	c.mu.Lock()
+	c.pieceBitmaps[host] |= 1 << pieceIndex
+	c.mu.Unlock()
 }
 
-// uploadWorker uploads pieces to a host as directed by reqChan. When there
-// are no more pieces to upload, it sends the final version of the
-// fileContract down respChan.
-func (f *file) uploadWorker(host uploader, reqChan chan uploadPiece, respChan chan fileContract) {
+// reset clears all recorded progress, in preparation for the next chunk.
+func (c *checkpointState) reset() {
+	c.mu.Lock()
+	c.pieceBitmaps = make(map[string]uint32)
+	c.mu.Unlock()
+}
+
+// snapshot returns a copy of the current piece bitmaps, suitable for
+// embedding in an uploadCheckpoint.
+func (c *checkpointState) snapshot() map[string]uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]uint32, len(c.pieceBitmaps))
+	for host, bitmap := range c.pieceBitmaps {
+		out[host] = bitmap
+	}
+	return out
+}
+
+// uploadWorker uploads pieces to a host as directed by reqChan. A piece
+// that a host fails to accept is handed to retryChan instead of being
+// dropped, so the coordinator can re-dispatch it to a different host. Once
+// a host has failed a piece it is considered unhealthy: the worker reports
+// itself dead on deadChan and exits without draining the rest of reqChan or
+// sending to respChan, since it no longer has anything useful to contribute.
+// If the worker instead drains reqChan to closure, it sends its final
+// fileContract down respChan. state may be nil, in which case no
+// checkpointing bookkeeping is performed; stats may be nil, in which case
+// no progress bookkeeping is performed.
+//
+// ctx is passed through to every addPiece call, so that cancelling it
+// aborts an in-flight RPC rather than waiting for it to finish naturally.
+func (f *file) uploadWorker(ctx context.Context, host uploader, reqChan chan uploadPiece, respChan chan fileContract, ackChan chan uploadAck, retryChan chan uploadPiece, deadChan chan struct{}, state *checkpointState, stats *progressTracker) {
+	hostIP := string(host.fileContract().IP)
 	for req := range reqChan {
-		err := host.addPiece(req)
+		if state != nil && state.hasPiece(hostIP, req.pieceIndex) {
+			// host already has this piece from a previous attempt at this
+			// chunk; count it as done without re-uploading it.
+			ackChan <- uploadAck{hostIP, req.pieceIndex}
+			continue
+		}
+		err := host.addPiece(ctx, req)
 		if err != nil {
-			// TODO: how should this be handled?
-			break
+			// Report death before handing the piece back for retry, so the
+			// coordinator's aliveHosts count is already accurate by the
+			// time it decides whether anyone is left to retry this piece
+			// against.
+			deadChan <- struct{}{}
+			retryChan <- req
+			return
 		}
 		atomic.AddUint64(&f.bytesUploaded, uint64(len(req.data)))
+		if state != nil {
+			state.ackPiece(hostIP, req.pieceIndex)
+		}
+		if stats != nil {
+			stats.recordPiece(hostIP, len(req.data))
+		}
+		ackChan <- uploadAck{hostIP, req.pieceIndex}
 	}
 	// reqChan was closed; send final fileContract
 	respChan <- host.fileContract()
 }
 
 // upload reads chunks from r and uploads them to hosts. It spawns a worker
-// for each host, and instructs them to upload pieces of each chunk.
-func (f *file) upload(r io.Reader, hosts []uploader) error {
+// for each host, and instructs them to upload pieces of each chunk. A piece
+// that a worker's host rejects is re-dispatched to another still-healthy
+// worker, up to maxPieceAttempts times, instead of being silently dropped.
+//
+// If a chunk ends up with fewer pieces placed than its erasure code's
+// MinPieces, upload aborts immediately with a *chunkFatalError, since the
+// chunk cannot be reconstructed. If every chunk clears MinPieces but some
+// fall short of the number of pieces attempted, upload finishes normally
+// but returns a *chunkDegradedError identifying the under-replicated
+// chunks, so the caller can repair them instead of treating the upload as
+// having failed outright.
+//
+// If renter is non-nil, upload checkpoints its progress to renter's persist
+// directory after every chunk that is fully resolved, under nickname, and
+// saves the finished .sia file itself once every chunk has been resolved
+// (whether at full health or degraded); the caller does not need to save it
+// again. If resumeCP is non-nil, upload resumes from resumeCP.ChunkIndex
+// instead of starting at the beginning of r; the caller is responsible for
+// having already advanced r's read position past any data that should not
+// be re-read (upload does not skip any data in r itself; it only numbers the
+// chunks it reads starting from resumeCP.ChunkIndex).
+//
+// If ctx is cancelled before every chunk has been uploaded, upload stops
+// dispatching new work, closes reqChan so idle workers exit, closes every
+// host connection, and returns ctx.Err(). Any chunk that had already
+// completed remains checkpointed, so a later ResumeUpload can pick up where
+// cancellation left off. If progressChan is non-nil, upload sends an
+// UploadProgress on it after every chunk is resolved (including the final,
+// cancelled or errored one); it never closes progressChan.
+func (f *file) upload(ctx context.Context, r io.Reader, hosts []uploader, renter *Renter, nickname, sourceFilename string, resumeCP *uploadCheckpoint, progressChan chan<- UploadProgress) error {
 	// All requests are sent down the same channel. Since all workers are
 	// waiting on this channel, pieces will be uploaded by the first idle
 	// worker. This means faster uploaders will get more pieces than slow
@@ -71,40 +238,277 @@ func (f *file) upload(r io.Reader, hosts []uploader) error {
 	// fileContracts from respChan and store them in f.
 	respChan := make(chan fileContract)
 
+	// ackChan lets uploadWorkers report successful piece deliveries back to
+	// the coordinator below. retryChan carries pieces a host failed to
+	// accept, for re-dispatch to another host. deadChan tells the
+	// coordinator a worker has given up and will neither read reqChan nor
+	// send to respChan again.
+	ackChan := make(chan uploadAck)
+	retryChan := make(chan uploadPiece)
+	deadChan := make(chan struct{})
+
+	startChunk := uint64(0)
+	if resumeCP != nil {
+		startChunk = resumeCP.ChunkIndex
+		for ip, contract := range resumeCP.Contracts {
+			f.contracts[ip] = contract
+		}
+	}
+	state := newCheckpointState()
+	stats := newProgressTracker()
+
 	// spawn workers
 	for _, h := range hosts {
-		go f.uploadWorker(h, reqChan, respChan)
+		go f.uploadWorker(ctx, h, reqChan, respChan, ackChan, retryChan, deadChan, state, stats)
 	}
 
+	// teardown closes reqChan (if it hasn't been already), drains the
+	// contracts of every worker still alive, and closes every host
+	// connection. It is called on every exit path so cancellation and
+	// normal completion tear down identically.
+	closed := false
+	teardown := func(aliveHosts int) {
+		if !closed {
+			close(reqChan)
+			closed = true
+		}
+		for a := 0; a < aliveHosts; a++ {
+			contract := <-respChan
+			f.contracts[contract.IP] = contract
+		}
+		for _, h := range hosts {
+			h.Close()
+		}
+	}
+
+	emitProgress := func(chunksDone uint64, err error) {
+		if progressChan == nil {
+			return
+		}
+		p := UploadProgress{
+			Nickname:      nickname,
+			ChunksDone:    chunksDone,
+			ChunksTotal:   f.numChunks(),
+			BytesUploaded: atomic.LoadUint64(&f.bytesUploaded),
+			BytesTotal:    f.size,
+			PerHostStats:  stats.snapshot(),
+			Err:           err,
+		}
+		if err != nil {
+			// A terminal event is always delivered: after a cancellation
+			// ctx.Done() is already closed, and selecting on it here would
+			// drop the very event that reports the cancellation.
+			progressChan <- p
+			return
+		}
+		select {
+		case progressChan <- p:
+		case <-ctx.Done():
+		}
+	}
+
+	aliveHosts := len(hosts)
+	var degradedChunks []uint64
+
 	// encode and upload each chunk
-	for i := uint64(0); ; i++ {
+	for i := startChunk; ; i++ {
+		if ctx.Err() != nil {
+			teardown(aliveHosts)
+			emitProgress(i, ctx.Err())
+			return ctx.Err()
+		}
+
 		// read next chunk
 		chunk := make([]byte, f.chunkSize())
 		_, err := io.ReadFull(r, chunk)
 		if err == io.EOF {
 			break
 		} else if err != nil && err != io.ErrUnexpectedEOF {
+			teardown(aliveHosts)
+			emitProgress(i, err)
 			return err
 		}
 		// encode
 		pieces, err := f.erasureCode.Encode(chunk)
 		if err != nil {
+			teardown(aliveHosts)
+			emitProgress(i, err)
 			return err
 		}
-		// send upload requests to workers
+
+		if aliveHosts == 0 {
+			teardown(aliveHosts)
+			err = &chunkFatalError{i, 0, f.erasureCode.MinPieces()}
+			emitProgress(i, err)
+			return err
+		}
+
+		// Track every piece of this chunk until it is either delivered or
+		// given up on, re-dispatching failures to whichever host is next
+		// available.
+		//
+		// cancelled is latched once ctx.Done() fires, rather than forcing
+		// pending to 0 immediately: a worker that is mid-addPiece at that
+		// instant will still report its outcome on ackChan or on
+		// deadChan/retryChan, and something has to keep receiving those
+		// until every already-dispatched piece is accounted for, or that
+		// worker blocks forever with nobody left to read from it. Once
+		// cancelled, any retried piece is given up on immediately instead
+		// of being re-dispatched, since no new work should start.
+		//
+		// retryQueue holds every piece still waiting for its turn on
+		// reqChan, seeded with the whole chunk up front rather than sent
+		// there directly: a chunk routinely has more pieces than there are
+		// live hosts (connectHosts only guarantees MinPieces, not
+		// NumPieces), so a plain "for _, p := range pieces { reqChan <- p
+		// }" blocks on the first piece no host is free to take, while every
+		// busy worker is itself blocked trying to report back on
+		// ackChan/deadChan/retryChan — and the coordinator, stuck on that
+		// send, is the only reader of those channels. Piece sends are
+		// instead just another case in this same select, gated on sendChan
+		// being non-nil, so dispatch, delivery, and failure handling are
+		// always interleaved and a send is never attempted against a
+		// reqChan nothing is free to read. The same reasoning is why a
+		// retry is queued here rather than resent via a detached goroutine:
+		// aliveHosts can drop to 0 between the moment a piece is queued and
+		// the moment a goroutine would get around to sending it, so
+		// aliveHosts (and cancellation) need to be re-checked fresh every
+		// time around the loop.
+		attempts := make(map[uint64]int, len(pieces))
+		cancelled := false
+		pending := len(pieces)
+		retryQueue := make([]uploadPiece, len(pieces))
 		for j, data := range pieces {
-			reqChan <- uploadPiece{data, i, uint64(j)}
+			attempts[uint64(j)] = 1
+			retryQueue[j] = uploadPiece{data, i, uint64(j)}
+		}
+		// done is nilled out the instant ctx.Done() is observed, so that
+		// case can never be selected again; left as ctx.Done() itself,
+		// a closed channel is always ready, and the loop would busy-spin
+		// re-selecting it for however long it takes the rest of the chunk's
+		// pieces to drain.
+		done := ctx.Done()
+		for pending > 0 {
+			var sendChan chan uploadPiece
+			var sendPiece uploadPiece
+			if len(retryQueue) > 0 && !cancelled && aliveHosts > 0 {
+				sendChan = reqChan
+				sendPiece = retryQueue[0]
+			}
+			select {
+			case <-done:
+				cancelled = true
+				done = nil
+				pending -= len(retryQueue)
+				retryQueue = nil
+			case <-ackChan:
+				pending--
+			case piece := <-retryChan:
+				if cancelled || aliveHosts == 0 || attempts[piece.pieceIndex] >= maxPieceAttempts {
+					// give up on this piece for this chunk
+					pending--
+					continue
+				}
+				attempts[piece.pieceIndex]++
+				retryQueue = append(retryQueue, piece)
+			case <-deadChan:
+				aliveHosts--
+				if aliveHosts == 0 {
+					// nobody is left to read reqChan; anything still queued
+					// for retry would never land, so give it up now instead
+					// of leaving pending stuck above 0 forever.
+					pending -= len(retryQueue)
+					retryQueue = nil
+				}
+			case sendChan <- sendPiece:
+				retryQueue = retryQueue[1:]
+			}
+		}
+		if ctx.Err() != nil {
+			teardown(aliveHosts)
+			emitProgress(i, ctx.Err())
+			return ctx.Err()
+		}
+
+		// tally how many distinct pieces of this chunk actually landed on
+		// at least one host
+		var placedMask uint32
+		for _, bitmap := range state.snapshot() {
+			placedMask |= bitmap
 		}
+		placed := bits.OnesCount32(placedMask)
+		if placed < f.erasureCode.MinPieces() {
+			teardown(aliveHosts)
+			err = &chunkFatalError{i, placed, f.erasureCode.MinPieces()}
+			emitProgress(i, err)
+			return err
+		}
+		if placed < len(pieces) {
+			degradedChunks = append(degradedChunks, i)
+		}
+
 		atomic.AddUint64(&f.chunksUploaded, 1)
+
+		if renter != nil {
+			cp := &uploadCheckpoint{
+				Nickname:       nickname,
+				Filename:       sourceFilename,
+				ChunkIndex:     i + 1,
+				PieceBitmaps:   state.snapshot(),
+				Contracts:      make(map[string]fileContract, len(hosts)),
+				DataPieces:     f.erasureCode.MinPieces(),
+				ParityPieces:   f.erasureCode.NumPieces() - f.erasureCode.MinPieces(),
+				PieceSize:      f.pieceSize,
+				MasterKey:      f.masterKey,
+				FileSize:       f.size,
+				FileMode:       f.mode,
+				DegradedChunks: degradedChunks,
+			}
+			for _, h := range hosts {
+				c := h.fileContract()
+				cp.Contracts[string(c.IP)] = c
+			}
+			if err := renter.saveCheckpoint(cp); err != nil {
+				teardown(aliveHosts)
+				emitProgress(i, err)
+				return err
+			}
+		}
+		state.reset()
+		emitProgress(i+1, nil)
 	}
 
-	// signal workers to send their contracts
-	close(reqChan)
-	for range hosts {
-		contract := <-respChan
-		f.contracts[contract.IP] = contract
+	// signal the surviving workers to send their contracts
+	teardown(aliveHosts)
+
+	if renter != nil {
+		if len(degradedChunks) == 0 {
+			if err := renter.deleteCheckpoint(nickname); err != nil {
+				emitProgress(f.numChunks(), err)
+				return err
+			}
+		}
+		// if degradedChunks is non-empty, the checkpoint is left on disk
+		// (with its final state already saved above) so that a repair pass
+		// can find the source file and the contracts placed so far.
+
+		// Persist the .sia file as part of the same terminal outcome the
+		// caller sees on progressChan, rather than leaving it to be saved
+		// separately afterward: a save failure here is the upload's real
+		// final error, not a second event tacked on after a successful one.
+		if err := renter.saveFile(f); err != nil {
+			emitProgress(f.numChunks(), err)
+			return err
+		}
 	}
 
+	if len(degradedChunks) > 0 {
+		err := &chunkDegradedError{degradedChunks}
+		emitProgress(f.numChunks(), err)
+		return err
+	}
+
+	emitProgress(f.numChunks(), nil)
 	return nil
 }
 
@@ -140,18 +544,56 @@ func (r *Renter) checkWalletBalance(up modules.FileUploadParams) error {
 }
 
 // Upload takes an upload parameters, which contain a file to upload, and then
-// creates a redundant copy of the file on the Sia network.
+// creates a redundant copy of the file on the Sia network. It blocks until
+// the upload finishes, and is a thin wrapper around UploadWithContext using
+// a context that is never cancelled.
 func (r *Renter) Upload(up modules.FileUploadParams) error {
+	progressChan, err := r.UploadWithContext(context.Background(), up)
+	if err != nil {
+		return err
+	}
+	var final UploadProgress
+	for p := range progressChan {
+		final = p
+	}
+
+	if _, ok := final.Err.(*chunkDegradedError); ok {
+		// The file is intact but under-replicated; a background repair has
+		// already been scheduled by UploadWithContext, so this is not
+		// treated as a failed upload.
+		return nil
+	} else if final.Err != nil {
+		return errors.New("failed to upload any file pieces")
+	}
+	return nil
+}
+
+// UploadWithContext behaves like Upload, but runs the upload asynchronously
+// and returns a channel of UploadProgress events instead of blocking. The
+// channel receives one event per completed chunk plus a final event when
+// the upload finishes, and is closed once that final event has been sent.
+// The final event's Err is nil on full success, a *chunkDegradedError if
+// the file is intact but under-replicated (in which case a background
+// repair is scheduled automatically), ctx.Err() if ctx was cancelled, a
+// *chunkFatalError or other error if the upload failed, or an error from
+// saving the finished .sia file. The .sia file is saved as part of
+// producing that final event, so a save failure is reported there rather
+// than as a separate event afterward.
+//
+// Errors returned directly (rather than through the channel) are setup
+// failures that occur before any piece is uploaded; connecting to hosts is
+// part of that setup, so such an error can follow contract negotiation.
+func (r *Renter) UploadWithContext(ctx context.Context, up modules.FileUploadParams) (<-chan UploadProgress, error) {
 	// TODO: This type of restriction is something that should be handled by
 	// the frontend, not the backend.
 	if filepath.Ext(up.Filename) != filepath.Ext(up.Nickname) {
-		return errors.New("nickname and file name must have the same extension")
+		return nil, errors.New("nickname and file name must have the same extension")
 	}
 
 	// Open the file.
 	handle, err := os.Open(up.Filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check for a nickname conflict.
@@ -159,21 +601,20 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	_, exists := r.files[up.Nickname]
 	r.mu.RUnlock(lockID)
 	if exists {
-		return errors.New("file with that nickname already exists")
+		return nil, errors.New("file with that nickname already exists")
+	}
+
+	// An interrupted upload for this nickname leaves behind a checkpoint;
+	// resuming it goes through ResumeUpload instead, since the erasure code,
+	// piece size, and hosts it started with must be reused rather than
+	// re-derived from up.
+	if r.hasCheckpoint(up.Nickname) {
+		return nil, errors.New("an interrupted upload already exists for that nickname; call ResumeUpload instead")
 	}
 
-	// Check that the file is less than 5 GiB.
 	fileInfo, err := handle.Stat()
 	if err != nil {
-		return err
-	}
-	// NOTE: The upload max of 5 GiB is temporary and therefore does not have
-	// a constant. This should be removed once micropayments + upload resuming
-	// are in place. 5 GiB is chosen to prevent confusion - on anybody's
-	// machine any file appearing to be under 5 GB will be below the hard
-	// limit.
-	if fileInfo.Size() > 5*1024*1024*1024 {
-		return errors.New("cannot upload a file larger than 5 GB")
+		return nil, err
 	}
 
 	// Fill in any missing upload params with sensible defaults.
@@ -183,6 +624,9 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	if up.ErasureCode == nil {
 		up.ErasureCode, _ = NewRSCode(defaultDataPieces, defaultParityPieces)
 	}
+	if up.ErasureCode.NumPieces() > maxCheckpointPieces {
+		return nil, fmt.Errorf("erasure code cannot use more than %v pieces total", maxCheckpointPieces)
+	}
 	if up.PieceSize == 0 {
 		if fileInfo.Size() > defaultPieceSize {
 			up.PieceSize = defaultPieceSize
@@ -194,7 +638,7 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	// Check that we have enough money to finance the upload.
 	err = r.checkWalletBalance(up)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Create file object.
@@ -203,17 +647,9 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 
 	// Select and connect to hosts.
 	totalsize := up.PieceSize * uint64(up.ErasureCode.NumPieces()) * f.numChunks()
-	var hosts []uploader
-	for _, host := range r.hostDB.RandomHosts(up.ErasureCode.NumPieces() * 3 / 2) {
-		host, err := r.newHostUploader(host, totalsize, up.Duration, f.masterKey)
-		if err != nil {
-			continue
-		}
-		defer host.Close()
-		hosts = append(hosts, host)
-	}
-	if len(hosts) < up.ErasureCode.MinPieces() {
-		return errors.New("not enough hosts to support upload")
+	hosts, err := r.connectHosts(up.ErasureCode.MinPieces(), up.ErasureCode.NumPieces()*3/2, totalsize, up.Duration, f.masterKey)
+	if err != nil {
+		return nil, err
 	}
 
 	// Add file to renter.
@@ -222,22 +658,132 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	r.save()
 	r.mu.Unlock(lockID)
 
-	// Upload in parallel.
-	err = f.upload(handle, hosts)
+	progressChan := make(chan UploadProgress)
+	go func() {
+		defer close(progressChan)
+
+		err := f.upload(ctx, handle, hosts, r, up.Nickname, up.Filename, nil, progressChan)
+		if degraded, ok := err.(*chunkDegradedError); ok {
+			// The file is intact but under-replicated; f.upload has already
+			// saved it, so only the background repair remains.
+			r.scheduleRepair(up.Nickname, degraded.chunks)
+			return
+		} else if err != nil {
+			// Upload failed outright, was cancelled, or the final save
+			// failed; remove the file object unless there's a checkpoint a
+			// future ResumeUpload can pick up from.
+			if !r.hasCheckpoint(up.Nickname) {
+				lockID := r.mu.Lock()
+				delete(r.files, up.Nickname)
+				r.save()
+				r.mu.Unlock(lockID)
+			}
+			return
+		}
+	}()
+
+	return progressChan, nil
+}
+
+// connectHosts selects up to sampleSize random hosts and negotiates a
+// storage contract with each of them for totalSize bytes over duration. It
+// returns an error if fewer than minHosts connections succeed.
+func (r *Renter) connectHosts(minHosts, sampleSize int, totalSize uint64, duration types.BlockHeight, masterKey crypto.TwofishKey) ([]uploader, error) {
+	var hosts []uploader
+	for _, host := range r.hostDB.RandomHosts(sampleSize) {
+		host, err := r.newHostUploader(host, totalSize, duration, masterKey)
+		if err != nil {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	if len(hosts) < minHosts {
+		// Every connection made it this far negotiated a real contract with
+		// its host; none of them are going to be used, so close them here
+		// rather than leaking the connection and the negotiated contract.
+		for _, h := range hosts {
+			h.Close()
+		}
+		return nil, errors.New("not enough hosts to support upload")
+	}
+	return hosts, nil
+}
+
+// ResumeUpload continues an upload that was interrupted mid-flight, using
+// the on-disk checkpoint left behind for nickname. It picks up at the last
+// chunk boundary the checkpoint recorded, so chunks that were fully uploaded
+// before the interruption are not re-uploaded; every piece of the chunk that
+// was in progress is uploaded again, including pieces a host had already
+// acknowledged. This
+// works across a full process restart, not just a cancel-then-resume within
+// the same process lifetime: if the crash happened before a .sia file was
+// ever written, the file metadata is rebuilt from the checkpoint itself. If
+// the checkpoint instead shows every chunk already resolved, the upload had
+// actually finished degraded, and resuming just schedules the repair that
+// was pending.
+func (r *Renter) ResumeUpload(nickname string) error {
+	cp, err := r.loadCheckpoint(nickname)
 	if err != nil {
-		// Upload failed; remove the file object.
-		lockID = r.mu.Lock()
-		delete(r.files, up.Nickname)
-		r.save()
+		return errors.New("no interrupted upload found for that nickname")
+	}
+
+	lockID := r.mu.RLock()
+	f, exists := r.files[nickname]
+	r.mu.RUnlock(lockID)
+	if !exists {
+		// No .sia file was ever written for this upload, which is expected
+		// if it crashed mid-chunk rather than merely being cancelled within
+		// the same process lifetime. Rebuild f from the checkpoint itself
+		// instead of giving up.
+		f, err = r.reconstituteFile(cp)
+		if err != nil {
+			return err
+		}
+		lockID := r.mu.Lock()
+		r.files[nickname] = f
 		r.mu.Unlock(lockID)
-		return errors.New("failed to upload any file pieces")
+	}
+	if f.erasureCode.NumPieces() > maxCheckpointPieces {
+		return fmt.Errorf("erasure code cannot use more than %v pieces total", maxCheckpointPieces)
 	}
 
-	// Save the .sia file to the renter directory.
-	err = r.saveFile(f)
+	if cp.ChunkIndex >= f.numChunks() {
+		// Every chunk was already resolved, so this checkpoint wasn't left
+		// behind by an interruption mid-upload; it's still on disk because
+		// the upload finished degraded. Re-running f.upload from here would
+		// skip straight to io.EOF without touching a single chunk, leaving
+		// degradedChunks empty and causing the checkpoint's DegradedChunks
+		// bookkeeping to be deleted as if the file were fully healthy.
+		// Go straight to the repair that bookkeeping exists for instead.
+		r.scheduleRepair(nickname, cp.DegradedChunks)
+		return nil
+	}
+
+	handle, err := os.Open(cp.Filename)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Skip past the chunks that were already fully uploaded.
+	for i := uint64(0); i < cp.ChunkIndex; i++ {
+		skip := make([]byte, f.chunkSize())
+		if _, err := io.ReadFull(handle, skip); err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+	}
+
+	totalsize := f.pieceSize * uint64(f.erasureCode.NumPieces()) * f.numChunks()
+	hosts, err := r.connectHosts(f.erasureCode.MinPieces(), f.erasureCode.NumPieces()*3/2, totalsize, defaultDuration, f.masterKey)
+	if err != nil {
+		return err
+	}
+
+	err = f.upload(context.Background(), handle, hosts, r, nickname, cp.Filename, cp, nil)
+	if degraded, ok := err.(*chunkDegradedError); ok {
+		// f.upload has already saved the .sia file; only the background
+		// repair remains.
+		r.scheduleRepair(nickname, degraded.chunks)
+		return nil
+	}
+	return err
 }
@@ -0,0 +1,228 @@
+package renter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHost is a minimal uploader used to drive file.upload without a real
+// network connection. By default addPiece always succeeds; failOn names
+// specific piece indices to fail instead, and block makes addPiece wait on
+// ctx.Done() before returning ctx.Err(), for exercising cancellation.
+type fakeHost struct {
+	contract fileContract
+	failOn   map[uint64]bool
+	block    bool
+	started  chan struct{} // closed once a blocking addPiece call begins waiting
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (h *fakeHost) addPiece(ctx context.Context, piece uploadPiece) error {
+	if h.block {
+		if h.started != nil {
+			close(h.started)
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if h.failOn[piece.pieceIndex] {
+		return errors.New("fakeHost: host rejected piece")
+	}
+	return nil
+}
+
+func (h *fakeHost) fileContract() fileContract { return h.contract }
+
+func (h *fakeHost) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+	return nil
+}
+
+// newTestFile returns a *file backed by a real Reed-Solomon code, suitable
+// for driving file.upload directly in tests.
+func newTestFile(t *testing.T, dataPieces, parityPieces int) *file {
+	t.Helper()
+	ec, err := NewRSCode(dataPieces, parityPieces)
+	if err != nil {
+		t.Fatalf("NewRSCode(%d, %d): %v", dataPieces, parityPieces, err)
+	}
+	return newFile("testfile", ec, smallPieceSize, 0)
+}
+
+// TestUploadRetriesFailedPieceToAnotherHost checks that a piece rejected by
+// one host is re-dispatched to another still-healthy host instead of being
+// dropped.
+func TestUploadRetriesFailedPieceToAnotherHost(t *testing.T) {
+	f := newTestFile(t, 1, 1) // MinPieces == 1, NumPieces == 2
+
+	bad := &fakeHost{contract: fileContract{IP: "bad-host"}, failOn: map[uint64]bool{0: true, 1: true}}
+	good := &fakeHost{contract: fileContract{IP: "good-host"}}
+	hosts := []uploader{bad, good}
+
+	r := bytes.NewReader(make([]byte, f.chunkSize()))
+	if err := f.upload(context.Background(), r, hosts, nil, "testfile", "testfile", nil, nil); err != nil {
+		t.Fatalf("upload: unexpected error: %v", err)
+	}
+
+	if !bad.closed || !good.closed {
+		t.Fatal("upload did not close every host connection")
+	}
+	if len(f.contracts) != 1 {
+		t.Fatalf("expected exactly the surviving host's contract, got %d contracts", len(f.contracts))
+	}
+	if _, ok := f.contracts["good-host"]; !ok {
+		t.Fatal("expected the good host's contract to be recorded; the retried piece never reached it")
+	}
+}
+
+// TestUploadMorePiecesThanHosts checks that a chunk with more pieces than
+// live hosts still completes instead of deadlocking. This is the common
+// real-world shape (e.g. the default 2-data/10-parity code with only a
+// handful of hosts connected): every host has to take more than one piece,
+// so the coordinator's initial dispatch has to keep interleaving with
+// delivery and failure handling instead of sending every piece up front.
+func TestUploadMorePiecesThanHosts(t *testing.T) {
+	tests := []struct {
+		name   string
+		failOn map[uint64]bool // pieces the flaky host rejects
+	}{
+		{"noFailures", nil},
+		{"withFailures", map[uint64]bool{0: true, 2: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFile(t, 2, 2) // MinPieces == 2, NumPieces == 4
+			flaky := &fakeHost{contract: fileContract{IP: "flaky-host"}, failOn: tt.failOn}
+			good := &fakeHost{contract: fileContract{IP: "good-host"}}
+			hosts := []uploader{flaky, good} // 2 hosts, 4 pieces
+
+			r := bytes.NewReader(make([]byte, f.chunkSize()))
+			done := make(chan error, 1)
+			go func() {
+				done <- f.upload(context.Background(), r, hosts, nil, "testfile", "testfile", nil, nil)
+			}()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("upload: unexpected error: %v", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("upload did not return with more pieces than hosts; the coordinator likely deadlocked")
+			}
+		})
+	}
+}
+
+// TestUploadDegradedVsFatal checks the threshold math that decides whether
+// a chunk finishes fully healthy, degraded but recoverable, or fatally
+// under-replicated.
+func TestUploadDegradedVsFatal(t *testing.T) {
+	tests := []struct {
+		name   string
+		failOn map[uint64]bool
+	}{
+		{"fullHealth", nil},
+		{"degraded", map[uint64]bool{3: true}},
+		{"fatal", map[uint64]bool{2: true, 3: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFile(t, 3, 1) // MinPieces == 3, NumPieces == 4
+			host := &fakeHost{contract: fileContract{IP: "host"}, failOn: tt.failOn}
+			r := bytes.NewReader(make([]byte, f.chunkSize()))
+
+			err := f.upload(context.Background(), r, []uploader{host}, nil, "testfile", "testfile", nil, nil)
+
+			switch tt.name {
+			case "fullHealth":
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			case "degraded":
+				if _, ok := err.(*chunkDegradedError); !ok {
+					t.Fatalf("expected *chunkDegradedError, got %v", err)
+				}
+			case "fatal":
+				if _, ok := err.(*chunkFatalError); !ok {
+					t.Fatalf("expected *chunkFatalError, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestUploadMultipleHostsFailingDoesNotDeadlock checks that a chunk still
+// resolves when two hosts fail at once, rather than hanging forever. A
+// retried piece must never be (re-)dispatched once every worker that could
+// have taken it has already reported itself dead, even when the coordinator
+// happens to process one worker's retry before it has processed another's
+// death.
+func TestUploadMultipleHostsFailingDoesNotDeadlock(t *testing.T) {
+	f := newTestFile(t, 1, 2) // MinPieces == 1, NumPieces == 3
+
+	failAll := map[uint64]bool{0: true, 1: true, 2: true}
+	badA := &fakeHost{contract: fileContract{IP: "bad-a"}, failOn: failAll}
+	badB := &fakeHost{contract: fileContract{IP: "bad-b"}, failOn: failAll}
+	good := &fakeHost{contract: fileContract{IP: "good-host"}}
+	hosts := []uploader{badA, badB, good}
+
+	r := bytes.NewReader(make([]byte, f.chunkSize()))
+	done := make(chan error, 1)
+	go func() {
+		done <- f.upload(context.Background(), r, hosts, nil, "testfile", "testfile", nil, nil)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("upload: unexpected error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("upload did not return with two hosts failing at once; the coordinator likely deadlocked")
+	}
+
+	if len(f.contracts) != 1 {
+		t.Fatalf("expected exactly the surviving host's contract, got %d contracts", len(f.contracts))
+	}
+	if _, ok := f.contracts["good-host"]; !ok {
+		t.Fatal("expected the good host's contract to be recorded; the retried pieces never reached it")
+	}
+}
+
+// TestUploadCancellationDoesNotDeadlock checks that cancelling ctx while a
+// host's addPiece RPC is in flight still lets upload return promptly,
+// rather than hanging forever waiting for a worker that has nobody left to
+// report to.
+func TestUploadCancellationDoesNotDeadlock(t *testing.T) {
+	f := newTestFile(t, 1, 0) // MinPieces == NumPieces == 1
+	host := &fakeHost{contract: fileContract{IP: "host"}, block: true, started: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := bytes.NewReader(make([]byte, f.chunkSize()))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f.upload(ctx, r, []uploader{host}, nil, "testfile", "testfile", nil, nil)
+	}()
+
+	<-host.started // wait until the worker is inside addPiece
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != ctx.Err() {
+			t.Fatalf("expected ctx.Err(), got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("upload did not return after ctx was cancelled mid-piece; the coordinator likely deadlocked")
+	}
+}